@@ -4,20 +4,22 @@ import (
 	"bytes"
 	"compress/gzip"
 	"fmt"
-	"io/fs"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
 	"github.com/supporttools/website/pkg/config"
+	"github.com/supporttools/website/pkg/health"
+	"github.com/supporttools/website/pkg/httpcache"
 	"github.com/supporttools/website/pkg/logging"
+	"github.com/supporttools/website/pkg/maintenance"
+	"github.com/supporttools/website/pkg/memfs"
 	"github.com/supporttools/website/pkg/metrics"
 )
 
@@ -25,20 +27,100 @@ var (
 	// Global logger variable
 	logger *logrus.Logger
 
-	// memoryFiles stores the content of each file keyed by its path
-	memoryFiles map[string]*fileData
+	// respCache fronts config.CFG.CacheProxyPath when configured; nil disables it.
+	respCache *httpcache.Cache
+	// cacheTarget is the parsed CacheProxyTarget, set alongside respCache.
+	cacheTarget *url.URL
 )
 
-type fileData struct {
-	contentType string
-	content     []byte
-	modTime     time.Time
+// passthroughMiddleware is the no-op cache middleware used when
+// config.CFG.CacheProxyPath is unset.
+func passthroughMiddleware(next http.Handler) http.Handler {
+	return next
+}
+
+// negotiateEncoding picks the best precompressed variant of fd the client
+// advertised support for via Accept-Encoding, preferring brotli over gzip.
+// It returns the encoding name ("" for the uncompressed body) and the bytes
+// to serve.
+func negotiateEncoding(r *http.Request, fd *memfs.FileData) (encoding string, body []byte) {
+	acceptEncoding := r.Header.Get("Accept-Encoding")
+	if fd.Br != nil && strings.Contains(acceptEncoding, "br") {
+		return "br", fd.Br
+	}
+	if fd.Gzip != nil && strings.Contains(acceptEncoding, "gzip") {
+		return "gzip", fd.Gzip
+	}
+	return "", fd.Raw
+}
+
+// statusFallbackMiddleware substitutes {webRoot}/{status}.html for error
+// responses (currently 404 and 500) that the wrapped handler would otherwise
+// send with an empty or generic body, so deployments can ship branded error
+// pages alongside their static assets. Responses are passed through
+// unmodified when no fallback file exists for the status.
+func statusFallbackMiddleware(webRoot string) func(http.Handler) http.Handler {
+	fallbacks := map[int]string{
+		http.StatusNotFound:            filepath.Join(webRoot, "404.html"),
+		http.StatusInternalServerError: filepath.Join(webRoot, "500.html"),
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			sfw := &statusFallbackWriter{ResponseWriter: w, fallbacks: fallbacks}
+			next.ServeHTTP(sfw, r)
+		})
+	}
+}
+
+// statusFallbackWriter buffers the decision on WriteHeader: if the status has
+// a configured fallback file, it serves that file's contents instead of
+// whatever the wrapped handler would have written.
+type statusFallbackWriter struct {
+	http.ResponseWriter
+	fallbacks   map[int]string
+	statusCode  int
+	wroteHeader bool
+	fellBack    bool
+}
+
+func (sfw *statusFallbackWriter) WriteHeader(code int) {
+	if sfw.wroteHeader {
+		return
+	}
+	sfw.wroteHeader = true
+	sfw.statusCode = code
+
+	if path, ok := sfw.fallbacks[code]; ok {
+		if content, err := os.ReadFile(path); err == nil {
+			sfw.fellBack = true
+			sfw.Header().Set("Content-Type", "text/html; charset=utf-8")
+			sfw.Header().Del("Content-Length")
+			sfw.ResponseWriter.WriteHeader(code)
+			_, _ = sfw.ResponseWriter.Write(content)
+			return
+		}
+	}
+
+	sfw.ResponseWriter.WriteHeader(code)
+}
+
+func (sfw *statusFallbackWriter) Write(b []byte) (int, error) {
+	if !sfw.wroteHeader {
+		sfw.WriteHeader(http.StatusOK)
+	}
+	if sfw.fellBack {
+		return len(b), nil
+	}
+	return sfw.ResponseWriter.Write(b)
 }
 
 func main() {
 	config.LoadConfiguration()
 	logger = logging.SetupLogging(&config.CFG)
 	logger.Info("Debug logging enabled")
+	memfs.SetLogger(logger)
+	maintenance.SetEnabled(config.CFG.MaintenanceMode)
 	if config.CFG.Debug {
 		logger.Infoln("Debug mode enabled")
 		logger.Infoln("Configuration:")
@@ -49,10 +131,40 @@ func main() {
 		logger.Infof("Use Memory: %t", config.CFG.UseMemory)
 	}
 
+	if config.CFG.CacheProxyPath != "" && config.CFG.CacheProxyTarget != "" {
+		logger.Infof("Fronting %s with httpcache, proxying to %s", config.CFG.CacheProxyPath, config.CFG.CacheProxyTarget)
+		target, err := url.Parse(config.CFG.CacheProxyTarget)
+		if err != nil {
+			logger.Fatalf("Invalid CACHE_PROXY_TARGET %q: %v", config.CFG.CacheProxyTarget, err)
+		}
+		cacheTarget = target
+		respCache = httpcache.New(config.CFG.CacheMaxBytes, config.CFG.CacheDefaultTTL, config.CFG.CacheSWR)
+		health.Register(health.NewChecker("upstream_cache", func() error {
+			return httpcache.Ping(cacheTarget)
+		}))
+	}
+
+	health.Register(health.NewChecker("access_log", logging.CheckAccessLogWritable))
+
 	if config.CFG.UseMemory {
 		logger.Infoln("Loading files into memory")
-		loadFilesIntoMemory(config.CFG.WebRoot)
+		if err := memfs.Load(config.CFG.WebRoot); err != nil {
+			logger.Fatalf("Failed to load files into memory: %v", err)
+		}
 		logger.Infoln("Files loaded into memory")
+
+		health.Register(health.NewChecker("memfs", func() error {
+			if !memfs.Loaded() {
+				return fmt.Errorf("in-memory filesystem not loaded")
+			}
+			return nil
+		}))
+
+		go func() {
+			if err := memfs.Watch(config.CFG.WebRoot); err != nil {
+				logger.Errorf("memfs watcher stopped: %v", err)
+			}
+		}()
 	}
 
 	go webserver()
@@ -64,13 +176,36 @@ func main() {
 func webserver() {
 	logger.Println("Starting web server...")
 
+	cacheMiddleware := passthroughMiddleware
+	if respCache != nil {
+		cacheMiddleware = respCache.Middleware(config.CFG.CacheProxyPath, cacheTarget)
+	}
+	statusFallback := statusFallbackMiddleware(config.CFG.WebRoot)
+	maintenanceMiddleware := maintenance.Middleware(config.CFG.MaintenanceFile)
+
 	if config.CFG.UseMemory {
 		logger.Println("Serving files from memory")
-		http.Handle("/", gzipMiddleware(promMiddleware(logRequest(http.HandlerFunc(serveFromMemory)))))
+		// serveFromMemory negotiates Accept-Encoding itself against
+		// precomputed gzip/brotli variants, so it bypasses gzipMiddleware.
+		var handler http.Handler = http.HandlerFunc(serveFromMemory)
+		handler = statusFallback(handler)
+		handler = cacheMiddleware(handler)
+		handler = maintenanceMiddleware(handler)
+		handler = logging.LogRequest(handler)
+		handler = promMiddleware(handler)
+		handler = logging.RequestIDMiddleware(handler)
+		http.Handle("/", handler)
 	} else {
 		logger.Println("Serving files directly from filesystem")
-		fs := http.FileServer(http.Dir(config.CFG.WebRoot))
-		http.Handle("/", gzipMiddleware(promMiddleware(logRequest(fs))))
+		var handler http.Handler = http.FileServer(http.Dir(config.CFG.WebRoot))
+		handler = statusFallback(handler)
+		handler = gzipMiddleware(handler)
+		handler = cacheMiddleware(handler)
+		handler = maintenanceMiddleware(handler)
+		handler = logging.LogRequest(handler)
+		handler = promMiddleware(handler)
+		handler = logging.RequestIDMiddleware(handler)
+		http.Handle("/", handler)
 	}
 
 	// Expose the registered Prometheus metrics via HTTP.
@@ -86,174 +221,69 @@ func promMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		startTime := time.Now()
 		sanitizedPath := sanitizePath(r.URL.Path)
-		logger.Infof("Processing request for: %s", sanitizedPath)
+		requestID := logging.RequestIDFromContext(r.Context())
+		logger.WithField("request_id", requestID).Infof("Processing request for: %s", sanitizedPath)
 		next.ServeHTTP(w, r)
 		duration := time.Since(startTime).Seconds()
-		logger.Infof("Request for %s processed in %f seconds", sanitizedPath, duration)
+		logger.WithField("request_id", requestID).Infof("Request for %s processed in %f seconds", sanitizedPath, duration)
 		metrics.RecordMetrics(sanitizedPath, duration)
 	})
 }
 
-// logRequest logs the request in the Nginx access log format
-func logRequest(handler http.Handler) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		// Get the real client IP, falling back to RemoteAddr if not behind Cloudflare
-		clientIP := r.Header.Get("CF-Connecting-IP")
-		if clientIP == "" {
-			clientIP = r.RemoteAddr
-		}
-
-		// Create a custom response writer to capture the status code and response size
-		lrw := &loggingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
-		handler.ServeHTTP(lrw, r)
-
-		// Format log like Nginx access log
-		logLine := fmt.Sprintf("%s - - [%s] \"%s %s %s\" %d %d \"%s\" \"%s\"",
-			clientIP, // $remote_addr (real client IP)
-			time.Now().Format("02/Jan/2006:15:04:05 -0700"), // [$time_local]
-			r.Method,         // $request method
-			r.RequestURI,     // $request URI
-			r.Proto,          // $request protocol
-			lrw.statusCode,   // $status
-			lrw.responseSize, // $body_bytes_sent
-			r.Referer(),      // $http_referer
-			r.UserAgent(),    // $http_user_agent
-		)
-		logger.Info(logLine)
-	}
-}
-
-type loggingResponseWriter struct {
-	http.ResponseWriter
-	statusCode   int
-	responseSize int
-}
-
-// Implement the http.ResponseWriter interface
-func (lrw *loggingResponseWriter) WriteHeader(code int) {
-	lrw.statusCode = code
-	lrw.ResponseWriter.WriteHeader(code)
-}
-
-// Implement the http.ResponseWriter interface
-func (lrw *loggingResponseWriter) Write(b []byte) (int, error) {
-	size, err := lrw.ResponseWriter.Write(b)
-	lrw.responseSize += size
-	return size, err
-}
-
-// loadFilesIntoMemory reads all files and directories from the web root directory into memory
-func loadFilesIntoMemory(rootDir string) {
-	memoryFiles = make(map[string]*fileData)
-	err := filepath.WalkDir(rootDir, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			logger.Printf("Error accessing path %q: %v\n", path, err)
-			return err
-		}
-
-		relPath, err := filepath.Rel(rootDir, path)
-		if err != nil {
-			logger.Printf("Failed to get relative path for %q: %v\n", path, err)
-			return err
-		}
-
-		// Normalize path for URL matching
-		urlPath := "/" + strings.Replace(relPath, string(filepath.Separator), "/", -1)
-
-		if d.IsDir() {
-			logger.Infof("Loading directory: %s\n", path)
-			// Ensure that the directory has a trailing slash for URL matching
-			if !strings.HasSuffix(urlPath, "/") {
-				urlPath += "/"
-			}
-
-			// Check if directory contains an index.html file
-			indexFilePath := filepath.Join(path, "index.html")
-			if _, err := os.Stat(indexFilePath); err == nil {
-				content, err := os.ReadFile(indexFilePath)
-				if err != nil {
-					logger.Printf("Failed to read index file %q: %v\n", indexFilePath, err)
-					return err
-				}
-				memoryFiles[urlPath] = &fileData{
-					contentType: http.DetectContentType(content),
-					content:     content,
-					modTime:     time.Now(),
-				}
-				logger.Infof("Directory %s loaded with index.html\n", urlPath)
-			} else {
-				// Directory has no index.html, could log this or handle differently if needed
-				logger.Infof("Directory %s does not contain an index.html\n", urlPath)
-			}
-		} else {
-			logger.Infof("Loading file: %s\n", path)
-
-			content, err := os.ReadFile(path)
-			if err != nil {
-				logger.Infof("Failed to read file %q: %v\n", path, err)
-				return err
-			}
-
-			memoryFiles[urlPath] = &fileData{
-				contentType: http.DetectContentType(content),
-				content:     content,
-				modTime:     time.Now(),
-			}
-
-			logger.Infof("File %s loaded into memory with urlPath %s\n", path, urlPath)
-		}
-		return nil
-	})
-	if err != nil {
-		logger.Fatalf("Failed to load files into memory: %v", err)
-	}
-
-	logger.Println("All files and directories successfully loaded into memory.")
-}
-
-// serveFromMemory serves files from memory
+// serveFromMemory serves files from the memfs in-memory snapshot
 func serveFromMemory(w http.ResponseWriter, r *http.Request) {
 	sanitizedPath := sanitizePath(r.URL.Path)
+	requestLogger := logger.WithField("request_id", logging.RequestIDFromContext(r.Context()))
 
-	logger.Infof("Serving request for: %s", sanitizedPath)
+	requestLogger.Infof("Serving request for: %s", sanitizedPath)
 
 	// If the path ends with a slash, try to serve the directory's index.html
 	if strings.HasSuffix(sanitizedPath, "/") {
-		logger.Infof("Request is for a directory, attempting to serve index.html for: %s", sanitizedPath)
+		requestLogger.Infof("Request is for a directory, attempting to serve index.html for: %s", sanitizedPath)
 		sanitizedPath += "index.html"
 	}
 
 	// Attempt to find the file in memory
-	fd, found := memoryFiles[sanitizedPath]
+	fd, found := memfs.Get(sanitizedPath)
 	if !found {
-		logger.Infof("File not found in memory for path: %s", sanitizedPath)
+		requestLogger.Infof("File not found in memory for path: %s", sanitizedPath)
 		// Attempt to serve the directory's index.html explicitly if not found with a trailing slash
 		if !strings.HasSuffix(sanitizedPath, "/index.html") {
 			indexPath := sanitizedPath + "/index.html"
-			if indexFd, indexFound := memoryFiles[indexPath]; indexFound {
+			if indexFd, indexFound := memfs.Get(indexPath); indexFound {
 				fd = indexFd
 				found = true
-				logger.Infof("Found index.html for path: %s", indexPath)
+				requestLogger.Infof("Found index.html for path: %s", indexPath)
 			}
 		}
 	}
 
 	// If still not found, return a 404
 	if !found {
-		logger.Infof("Returning 404 for path: %s", sanitizedPath)
+		requestLogger.Infof("Returning 404 for path: %s", sanitizedPath)
 		http.NotFound(w, r)
 		return
 	}
 
-	logger.WithField("path", sanitizedPath).Info("Serving file from memory")
+	requestLogger.WithField("path", sanitizedPath).Info("Serving file from memory")
+
+	encoding, body := negotiateEncoding(r, fd)
+
 	w.Header().Set("Cache-Control", "max-age=31536000")
 	w.Header().Set("X-Content-Type-Options", "nosniff")
-	w.Header().Set("Content-Length", strconv.Itoa(len(fd.content)))
-	w.Header().Set("Last-Modified", fd.modTime.UTC().Format(http.TimeFormat))
-	w.Header().Set("ETag", `"`+strconv.FormatInt(fd.modTime.Unix(), 10)+`"`)
-	w.Header().Set("Content-Type", fd.contentType)
+	w.Header().Set("Vary", "Accept-Encoding")
+	w.Header().Set("Last-Modified", fd.ModTime.UTC().Format(http.TimeFormat))
+	w.Header().Set("ETag", `"`+fd.SHA256+`"`)
+	w.Header().Set("Content-Type", fd.ContentType)
+	if encoding != "" {
+		w.Header().Set("Content-Encoding", encoding)
+	}
 
-	http.ServeContent(w, r, sanitizedPath, fd.modTime, bytes.NewReader(fd.content))
+	// http.ServeContent leaves Content-Length unset once Content-Encoding is
+	// set, so set it here from whichever variant (raw/gzip/br) we picked;
+	// ServeContent still overrides it for range requests.
+	w.Header().Set("Content-Length", fmt.Sprint(len(body)))
+	http.ServeContent(w, r, sanitizedPath, fd.ModTime, bytes.NewReader(body))
 }
 
 // sanitizePath sanitizes the path by escaping special characters and removing control characters
@@ -3,7 +3,9 @@ package config
 import (
 	"log"
 	"os"
+	"path/filepath"
 	"strconv"
+	"time"
 )
 
 // AppConfig structure for environment-based configurations.
@@ -12,6 +14,43 @@ type AppConfig struct {
 	Port        int    `json:"port"`
 	MetricsPort int    `json:"metricsPort"`
 	WebRoot     string `json:"webroot"`
+	LogFormat   string `json:"logFormat"`
+
+	// UseMemory selects whether requests are served from the in-memory
+	// snapshot (pkg/memfs) instead of directly off disk.
+	UseMemory bool `json:"useMemory"`
+
+	// CompressMinBytes is the minimum size, in bytes, a text-like asset must
+	// reach before pkg/memfs bothers precomputing gzip/brotli
+	// variants for it.
+	CompressMinBytes int `json:"compressMinBytes"`
+
+	// AdminToken guards the metrics-port admin endpoints (e.g. memfs's
+	// POST /admin/reload). Those endpoints refuse every request while empty.
+	AdminToken string `json:"-"`
+
+	// CacheProxyPath is the URL path prefix fronted by pkg/httpcache (e.g.
+	// "/blog/"). The cache middleware is disabled while this is empty.
+	CacheProxyPath string `json:"cacheProxyPath"`
+	// CacheProxyTarget is the upstream base URL that CacheProxyPath is
+	// proxied to on cache miss or stale-refresh.
+	CacheProxyTarget string `json:"cacheProxyTarget"`
+	// CacheMaxBytes bounds the total size of cached response bodies.
+	CacheMaxBytes int64 `json:"cacheMaxBytes"`
+	// CacheDefaultTTL is how long a cached response is served without
+	// revalidation.
+	CacheDefaultTTL time.Duration `json:"cacheDefaultTTL"`
+	// CacheSWR is the stale-while-revalidate window: how long past
+	// CacheDefaultTTL a stale response is still served while it refreshes
+	// in the background.
+	CacheSWR time.Duration `json:"cacheSWR"`
+
+	// MaintenanceFile is served in place of every response while
+	// MaintenanceMode is on.
+	MaintenanceFile string `json:"maintenanceFile"`
+	// MaintenanceMode starts the server in maintenance mode; it can also be
+	// toggled at runtime via POST /admin/maintenance.
+	MaintenanceMode bool `json:"maintenanceMode"`
 }
 
 var CFG AppConfig
@@ -22,6 +61,17 @@ func LoadConfiguration() {
 	CFG.Port = parseEnvInt("PORT", 8080)                    // Assuming 8080 as the default port
 	CFG.MetricsPort = parseEnvInt("METRICS_PORT", 9090)     // Assuming 9090 as the default port
 	CFG.WebRoot = getEnvOrDefault("WEBROOT", "/app/public") // Assuming "/app/public" as the default web root
+	CFG.LogFormat = getEnvOrDefault("LOG_FORMAT", "text")   // Assuming plaintext access logs as the default
+	CFG.UseMemory = parseEnvBool("USE_MEMORY", true)        // Assuming in-memory serving as the default
+	CFG.CompressMinBytes = parseEnvInt("COMPRESS_MIN_BYTES", 1024)
+	CFG.AdminToken = getEnvOrDefault("ADMIN_TOKEN", "")
+	CFG.CacheProxyPath = getEnvOrDefault("CACHE_PROXY_PATH", "")
+	CFG.CacheProxyTarget = getEnvOrDefault("CACHE_PROXY_TARGET", "")
+	CFG.CacheMaxBytes = parseEnvInt64("CACHE_MAX_BYTES", 64*1024*1024) // 64MB default
+	CFG.CacheDefaultTTL = parseEnvDuration("CACHE_DEFAULT_TTL", 60*time.Second)
+	CFG.CacheSWR = parseEnvDuration("CACHE_SWR", 30*time.Second)
+	CFG.MaintenanceMode = parseEnvBool("MAINTENANCE_MODE", false)
+	CFG.MaintenanceFile = getEnvOrDefault("MAINTENANCE_FILE", filepath.Join(CFG.WebRoot, "maintenance.html"))
 }
 
 func getEnvOrDefault(key, defaultValue string) string {
@@ -44,6 +94,32 @@ func parseEnvInt(key string, defaultValue int) int {
 	return intValue
 }
 
+func parseEnvInt64(key string, defaultValue int64) int64 {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	intValue, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		log.Printf("Error parsing %s as int64: %v. Using default value: %d", key, err, defaultValue)
+		return defaultValue
+	}
+	return intValue
+}
+
+func parseEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	durationValue, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Error parsing %s as duration: %v. Using default value: %s", key, err, defaultValue)
+		return defaultValue
+	}
+	return durationValue
+}
+
 func parseEnvBool(key string, defaultValue bool) bool {
 	value, exists := os.LookupEnv(key)
 	if !exists {
@@ -4,8 +4,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sync"
 
+	"github.com/supporttools/website/pkg/config"
 	"github.com/supporttools/website/pkg/logging"
+	"github.com/supporttools/website/pkg/version"
 )
 
 // VersionInfo represents the structure of version information.
@@ -15,26 +18,103 @@ type VersionInfo struct {
 	BuildTime string `json:"buildTime"`
 }
 
-var logger = logging.SetupLogging()
-var version = "MISSING VERSION INFO"
-var GitCommit = "MISSING GIT COMMIT"
-var BuildTime = "MISSING BUILD TIME"
+var logger = logging.SetupLogging(&config.CFG)
 
+// Checker is a single readiness probe: a named subsystem that can report
+// whether it's ready to serve traffic.
+type Checker interface {
+	Name() string
+	Check() error
+}
+
+type checkerFunc struct {
+	name string
+	fn   func() error
+}
+
+func (c checkerFunc) Name() string { return c.name }
+func (c checkerFunc) Check() error { return c.fn() }
+
+// NewChecker builds a Checker from a name and a check function.
+func NewChecker(name string, fn func() error) Checker {
+	return checkerFunc{name: name, fn: fn}
+}
+
+var (
+	checkersMu sync.Mutex
+	checkers   []Checker
+)
+
+// Register adds a readiness probe. Callers typically register their probes
+// from main() during startup, before the metrics server starts.
+func Register(c Checker) {
+	checkersMu.Lock()
+	defer checkersMu.Unlock()
+	checkers = append(checkers, c)
+}
+
+// HealthzHandler is the liveness probe: it reports "ok" as long as the
+// process is up, regardless of whether it's ready to serve traffic.
 func HealthzHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		fmt.Fprintf(w, "ok")
+		fmt.Fprint(w, "ok")
+	}
+}
+
+// checkResult is one Checker's outcome, rendered by ReadyzHandler.
+type checkResult struct {
+	Name  string `json:"name"`
+	Ready bool   `json:"ready"`
+	Error string `json:"error,omitempty"`
+}
+
+// readyzResponse is the JSON body rendered by ReadyzHandler.
+type readyzResponse struct {
+	Ready  bool          `json:"ready"`
+	Checks []checkResult `json:"checks"`
+}
+
+// ReadyzHandler is the readiness probe: it returns 503 until every
+// registered Checker reports ready, rendering each check's status as JSON.
+func ReadyzHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		checkersMu.Lock()
+		probes := make([]Checker, len(checkers))
+		copy(probes, checkers)
+		checkersMu.Unlock()
+
+		resp := readyzResponse{Ready: true}
+		for _, c := range probes {
+			result := checkResult{Name: c.Name(), Ready: true}
+			if err := c.Check(); err != nil {
+				result.Ready = false
+				result.Error = err.Error()
+				resp.Ready = false
+			}
+			resp.Checks = append(resp.Checks, result)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if !resp.Ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			logger.Error("Failed to encode readyz response", err)
+		}
 	}
 }
 
-// VersionHandler returns version information as JSON.
+// VersionHandler returns version information as JSON, sourced from
+// pkg/version so /healthz-adjacent endpoints and the build-time ldflags
+// agree on a single version.
 func VersionHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		logger.Info("VersionHandler")
 
 		versionInfo := VersionInfo{
-			Version:   version,
-			GitCommit: GitCommit,
-			BuildTime: BuildTime,
+			Version:   version.Version,
+			GitCommit: version.GitCommit,
+			BuildTime: version.BuildTime,
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,248 @@
+// Package httpcache is a small bounded LRU cache for proxied upstream
+// responses, with stale-while-revalidate semantics: a hit within TTL returns
+// immediately, a hit within the stale window returns the stale body while a
+// single background fetch refreshes it, and a miss fetches synchronously.
+package httpcache
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sync/singleflight"
+)
+
+var (
+	hitsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "httpcache_hits_total",
+		Help: "Number of requests served from a fresh cache entry.",
+	})
+	missesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "httpcache_misses_total",
+		Help: "Number of requests that required a synchronous upstream fetch.",
+	})
+	staleServedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "httpcache_stale_served_total",
+		Help: "Number of requests served a stale entry while it refreshed in the background.",
+	})
+	cacheBytes = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "httpcache_bytes",
+		Help: "Total bytes currently held in the cache.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(hitsTotal, missesTotal, staleServedTotal, cacheBytes)
+}
+
+// entry is a single cached upstream response.
+type entry struct {
+	status    int
+	header    http.Header
+	body      []byte
+	fetchedAt time.Time
+	ttl       time.Duration
+	swr       time.Duration
+}
+
+func (e *entry) fresh(now time.Time) bool {
+	return now.Before(e.fetchedAt.Add(e.ttl))
+}
+
+func (e *entry) servable(now time.Time) bool {
+	return now.Before(e.fetchedAt.Add(e.ttl + e.swr))
+}
+
+type cacheItem struct {
+	key   string
+	entry *entry
+}
+
+// Cache is a bounded, in-process LRU of upstream responses.
+type Cache struct {
+	mu       sync.Mutex
+	ll       *list.List
+	items    map[string]*list.Element
+	maxBytes int64
+	curBytes int64
+
+	defaultTTL time.Duration
+	swr        time.Duration
+
+	group singleflight.Group
+}
+
+// New returns a Cache bounded to maxBytes of response bodies, using
+// defaultTTL and swr (stale-while-revalidate window) for every entry.
+func New(maxBytes int64, defaultTTL, swr time.Duration) *Cache {
+	return &Cache{
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+		maxBytes:   maxBytes,
+		defaultTTL: defaultTTL,
+		swr:        swr,
+	}
+}
+
+// Middleware returns a middleware that serves GET requests under pathPrefix
+// from the cache, proxying to target on miss or stale-refresh. Requests
+// outside pathPrefix, or with methods other than GET, pass through to next
+// unmodified.
+func (c *Cache) Middleware(pathPrefix string, target *url.URL) func(http.Handler) http.Handler {
+	proxy := httputil.NewSingleHostReverseProxy(target)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if pathPrefix == "" || r.Method != http.MethodGet || !strings.HasPrefix(r.URL.Path, pathPrefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := cacheKey(r)
+			now := time.Now()
+
+			// Read the cached entry pointer under c.mu: store() mutates
+			// cacheItem.entry in place on a refresh, so an unsynchronized
+			// read here races with a concurrent background revalidation.
+			c.mu.Lock()
+			el, found := c.items[key]
+			var e *entry
+			if found {
+				e = el.Value.(*cacheItem).entry
+			}
+			c.mu.Unlock()
+
+			if found {
+				if e.fresh(now) {
+					hitsTotal.Inc()
+					c.touch(el)
+					writeEntry(w, e)
+					return
+				}
+				if e.servable(now) {
+					staleServedTotal.Inc()
+					writeEntry(w, e)
+					// Detach from the inbound request's context: net/http
+					// cancels it the instant this ServeHTTP call returns,
+					// which would abort the background refresh before it
+					// ever reaches upstream.
+					bgReq := r.Clone(context.WithoutCancel(r.Context()))
+					go c.fetch(key, bgReq, proxy, nil)
+					return
+				}
+			}
+
+			missesTotal.Inc()
+			c.fetch(key, r, proxy, w)
+		})
+	}
+}
+
+// fetch proxies r to upstream, caches the response under key, and writes it
+// to w when w is non-nil (synchronous miss). Concurrent fetches for the same
+// key are collapsed via singleflight.
+func (c *Cache) fetch(key string, r *http.Request, proxy *httputil.ReverseProxy, w http.ResponseWriter) {
+	result, _, _ := c.group.Do(key, func() (interface{}, error) {
+		rr := &responseRecorder{header: make(http.Header), status: http.StatusOK}
+		proxy.ServeHTTP(rr, r)
+
+		e := &entry{
+			status:    rr.status,
+			header:    rr.header,
+			body:      rr.body.Bytes(),
+			fetchedAt: time.Now(),
+			ttl:       c.defaultTTL,
+			swr:       c.swr,
+		}
+		c.store(key, e)
+		return e, nil
+	})
+
+	if w != nil {
+		writeEntry(w, result.(*entry))
+	}
+}
+
+func (c *Cache) store(key string, e *entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		old := el.Value.(*cacheItem)
+		c.curBytes -= int64(len(old.entry.body))
+		old.entry = e
+		c.ll.MoveToFront(el)
+	} else {
+		c.items[key] = c.ll.PushFront(&cacheItem{key: key, entry: e})
+	}
+	c.curBytes += int64(len(e.body))
+
+	for c.curBytes > c.maxBytes {
+		back := c.ll.Back()
+		if back == nil {
+			break
+		}
+		evicted := back.Value.(*cacheItem)
+		c.curBytes -= int64(len(evicted.entry.body))
+		c.ll.Remove(back)
+		delete(c.items, evicted.key)
+	}
+
+	cacheBytes.Set(float64(c.curBytes))
+}
+
+func (c *Cache) touch(el *list.Element) {
+	c.mu.Lock()
+	c.ll.MoveToFront(el)
+	c.mu.Unlock()
+}
+
+// cacheKey canonicalizes the request into a cache key: path plus sorted
+// query string, ignoring headers (host-based routing is handled by target).
+func cacheKey(r *http.Request) string {
+	return r.URL.Path + "?" + r.URL.Query().Encode()
+}
+
+// responseRecorder captures a proxied response so it can be cached and
+// replayed without a second upstream round-trip.
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (rr *responseRecorder) Header() http.Header { return rr.header }
+
+func (rr *responseRecorder) WriteHeader(status int) { rr.status = status }
+
+func (rr *responseRecorder) Write(b []byte) (int, error) { return rr.body.Write(b) }
+
+// Ping issues a short-timeout HEAD request against target to verify the
+// proxied upstream is reachable. It's meant to back a readiness probe.
+func Ping(target *url.URL) error {
+	client := http.Client{Timeout: 2 * time.Second}
+	resp, err := client.Head(target.String())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return nil
+}
+
+func writeEntry(w http.ResponseWriter, e *entry) {
+	header := w.Header()
+	for k, values := range e.header {
+		for _, v := range values {
+			header.Add(k, v)
+		}
+	}
+	w.WriteHeader(e.status)
+	_, _ = w.Write(e.body)
+}
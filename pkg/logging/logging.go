@@ -1,11 +1,15 @@
 package logging
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,8 +20,52 @@ import (
 var (
 	logger        *logrus.Logger
 	accessLogFile *os.File
+	accessLogger  *logrus.Logger
 )
 
+// RequestIDHeader is the header used to read and echo the request ID.
+const RequestIDHeader = "X-Request-ID"
+
+type contextKey string
+
+const requestIDContextKey contextKey = "requestID"
+
+// WithRequestID returns a copy of ctx carrying the given request ID.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey, requestID)
+}
+
+// RequestIDFromContext returns the request ID stored in ctx, or "" if none is set.
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDContextKey).(string)
+	return requestID
+}
+
+// generateRequestID returns a random 16-byte hex-encoded identifier.
+func generateRequestID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// RequestIDMiddleware ensures every request carries a request ID, reusing the
+// X-Request-ID header when the caller supplied one or generating a new one
+// otherwise. The ID is echoed back on the response and attached to the
+// request context so downstream handlers, access logs, and metrics can all
+// tie back to the same request.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if requestID == "" {
+			requestID = generateRequestID()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+		next.ServeHTTP(w, r.WithContext(WithRequestID(r.Context(), requestID)))
+	})
+}
+
 func LogCallerInfo() *logrus.Entry {
 	_, filename, line, ok := runtime.Caller(1)
 	if !ok {
@@ -71,6 +119,11 @@ func SetupLogging(cfg *config.AppConfig) *logrus.Logger {
 		logger.Fatalf("Failed to open access log file: %v", err)
 	}
 
+	// accessLogger emits one JSON record per request when LOG_FORMAT=json.
+	accessLogger = logrus.New()
+	accessLogger.SetOutput(accessLogFile)
+	accessLogger.SetFormatter(&logrus.JSONFormatter{})
+
 	return logger
 }
 func GetRelativePath(filePath string) (string, error) {
@@ -101,9 +154,27 @@ func CloseAccessLog() {
 	}
 }
 
-// LogRequest logs HTTP requests to the access log file
+// CheckAccessLogWritable reports whether the access log file is open and
+// accessible, for use as a readiness probe.
+func CheckAccessLogWritable() error {
+	if accessLogFile == nil {
+		return fmt.Errorf("access log file not open")
+	}
+	if _, err := accessLogFile.Stat(); err != nil {
+		return fmt.Errorf("access log file unavailable: %w", err)
+	}
+	return nil
+}
+
+// LogRequest logs HTTP requests to the access log file. config.CFG.LogFormat
+// selects the on-disk format: "json" emits one structured logrus record per
+// request (remote_ip, method, uri, status, bytes, duration_ms, referer,
+// user_agent, vhost, request_id); anything else keeps the legacy Nginx-style
+// plaintext line so existing log shippers keep working unchanged.
 func LogRequest(handler http.Handler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
 		vHost := r.Host
 		clientIP := r.Header.Get("CF-Connecting-IP")
 		if clientIP == "" {
@@ -118,11 +189,28 @@ func LogRequest(handler http.Handler) http.HandlerFunc {
 		proto := r.Proto
 		userAgent := r.Header.Get("User-Agent")
 		referer := r.Header.Get("Referer")
+		requestID := RequestIDFromContext(r.Context())
 
 		// Capture the response status and size
 		lrw := &loggingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
 		handler.ServeHTTP(lrw, r)
 
+		if strings.EqualFold(config.CFG.LogFormat, "json") {
+			accessLogger.WithFields(logrus.Fields{
+				"remote_ip":   clientIP,
+				"method":      method,
+				"uri":         uri,
+				"status":      lrw.statusCode,
+				"bytes":       lrw.responseSize,
+				"duration_ms": float64(time.Since(start)) / float64(time.Millisecond),
+				"referer":     referer,
+				"user_agent":  userAgent,
+				"vhost":       vHost,
+				"request_id":  requestID,
+			}).Info("access")
+			return
+		}
+
 		// Format the current time in UTC
 		timestamp := time.Now().UTC().Format("02/Jan/2006:15:04:05 -0700")
 
@@ -0,0 +1,81 @@
+// Package maintenance provides a toggleable maintenance-mode middleware,
+// modeled on gitlab-workhorse's deploy page: while enabled, every request is
+// short-circuited with HTTP 503 and the contents of a configured HTML file.
+package maintenance
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sync/atomic"
+
+	"github.com/supporttools/website/pkg/config"
+)
+
+var enabled atomic.Bool
+
+// Enabled reports whether maintenance mode is currently active.
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// SetEnabled toggles maintenance mode. Call it once at startup with
+// config.CFG.MaintenanceMode, and thereafter from AdminHandler.
+func SetEnabled(on bool) {
+	enabled.Store(on)
+}
+
+// Middleware short-circuits every request with HTTP 503 and the contents of
+// filePath while maintenance mode is enabled, skipping cache headers and
+// setting Retry-After so clients and CDNs know to come back later.
+func Middleware(filePath string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !Enabled() {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			content, err := os.ReadFile(filePath)
+			if err != nil {
+				content = []byte("Service temporarily unavailable for maintenance.")
+			}
+
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Header().Set("Retry-After", "300")
+			w.Header().Del("Cache-Control")
+			w.Header().Del("ETag")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write(content)
+		})
+	}
+}
+
+// AdminHandler toggles maintenance mode when called with POST and the token
+// configured via config.CFG.AdminToken. It is meant to be mounted on the
+// metrics port only, never the public one.
+func AdminHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if config.CFG.AdminToken == "" || r.Header.Get("X-Admin-Token") != config.CFG.AdminToken {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		switch r.URL.Query().Get("enabled") {
+		case "true":
+			SetEnabled(true)
+		case "false":
+			SetEnabled(false)
+		default:
+			http.Error(w, `missing or invalid "enabled" query parameter`, http.StatusBadRequest)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "maintenance mode: %t\n", Enabled())
+	}
+}
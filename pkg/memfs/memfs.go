@@ -0,0 +1,350 @@
+// Package memfs is an in-memory, hot-reloadable mirror of a directory tree.
+// Files are loaded with precomputed gzip/brotli variants and served from an
+// atomic.Pointer snapshot so readers never block while a reload is in
+// progress.
+package memfs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/fsnotify/fsnotify"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/supporttools/website/pkg/config"
+)
+
+// logger defaults to a bare logrus.Logger so package functions are safe to
+// call before SetLogger runs; main() calls SetLogger with the application's
+// configured logger once logging.SetupLogging has run.
+var logger = logrus.New()
+
+// SetLogger replaces memfs's logger. Call it once at startup, after
+// logging.SetupLogging has configured l.
+func SetLogger(l *logrus.Logger) {
+	logger = l
+}
+
+// debounceInterval batches bursts of filesystem events (e.g. an editor or a
+// deploy writing many files in a row) into a single reload.
+const debounceInterval = 500 * time.Millisecond
+
+// FileData holds an in-memory asset along with precomputed gzip/brotli
+// variants so callers never have to compress on the request path. Gzip and Br
+// are nil when the asset was skipped (already-compressed type or below
+// config.CFG.CompressMinBytes).
+type FileData struct {
+	ContentType string
+	Raw         []byte
+	Gzip        []byte
+	Br          []byte
+	SHA256      string
+	ModTime     time.Time
+}
+
+var (
+	store  atomic.Pointer[map[string]*FileData]
+	loaded atomic.Bool
+
+	filesLoaded = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "memfs_files_loaded",
+		Help: "Number of files currently held in the in-memory filesystem.",
+	})
+	bytesLoaded = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "memfs_bytes_loaded",
+		Help: "Total raw bytes currently held in the in-memory filesystem.",
+	})
+	lastReloadTimestamp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "memfs_last_reload_timestamp",
+		Help: "Unix timestamp of the last successful reload.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(filesLoaded, bytesLoaded, lastReloadTimestamp)
+}
+
+// Get returns the in-memory asset for urlPath, if one is currently loaded.
+func Get(urlPath string) (*FileData, bool) {
+	m := store.Load()
+	if m == nil {
+		return nil, false
+	}
+	fd, found := (*m)[urlPath]
+	return fd, found
+}
+
+// Loaded reports whether an initial Load has completed and no reload is
+// currently in flight.
+func Loaded() bool {
+	return loaded.Load()
+}
+
+// Load walks rootDir and atomically swaps it in as the new in-memory
+// filesystem snapshot. Existing readers keep serving the previous snapshot
+// until the swap completes.
+func Load(rootDir string) error {
+	files := make(map[string]*FileData)
+	var totalBytes int
+
+	err := filepath.WalkDir(rootDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			logger.Printf("memfs: error accessing path %q: %v\n", path, err)
+			return err
+		}
+
+		relPath, err := filepath.Rel(rootDir, path)
+		if err != nil {
+			logger.Printf("memfs: failed to get relative path for %q: %v\n", path, err)
+			return err
+		}
+
+		urlPath := "/" + strings.Replace(relPath, string(filepath.Separator), "/", -1)
+
+		if d.IsDir() {
+			if !strings.HasSuffix(urlPath, "/") {
+				urlPath += "/"
+			}
+
+			indexFilePath := filepath.Join(path, "index.html")
+			if _, err := os.Stat(indexFilePath); err == nil {
+				content, err := os.ReadFile(indexFilePath)
+				if err != nil {
+					logger.Printf("memfs: failed to read index file %q: %v\n", indexFilePath, err)
+					return err
+				}
+				files[urlPath] = newFileData(content)
+				totalBytes += len(content)
+			}
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			logger.Printf("memfs: failed to read file %q: %v\n", path, err)
+			return err
+		}
+		files[urlPath] = newFileData(content)
+		totalBytes += len(content)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	store.Store(&files)
+	loaded.Store(true)
+
+	filesLoaded.Set(float64(len(files)))
+	bytesLoaded.Set(float64(totalBytes))
+	lastReloadTimestamp.SetToCurrentTime()
+
+	logger.Infof("memfs: loaded %d files (%d bytes) from %s", len(files), totalBytes, rootDir)
+	return nil
+}
+
+// Reload re-runs Load against rootDir. Readiness is marked unavailable for
+// the duration of the reload so health checks can report it as in-flight.
+func Reload(rootDir string) error {
+	loaded.Store(false)
+	err := Load(rootDir)
+	if err != nil && store.Load() != nil {
+		// The previous snapshot is still being served.
+		loaded.Store(true)
+	}
+	return err
+}
+
+// Watch starts an fsnotify watcher on rootDir (recursively) and reloads the
+// in-memory snapshot whenever files change, debouncing bursts of events into
+// a single reload. It also reloads on SIGHUP. Watch blocks until the watcher
+// fails, so callers should run it in a goroutine.
+func Watch(rootDir string) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	if err := addRecursive(watcher, rootDir); err != nil {
+		return err
+	}
+
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	defer signal.Stop(sighup)
+
+	reload := make(chan struct{}, 1)
+	var debounce *time.Timer
+	scheduleReload := func() {
+		if debounce == nil {
+			debounce = time.AfterFunc(debounceInterval, func() {
+				reload <- struct{}{}
+			})
+			return
+		}
+		debounce.Reset(debounceInterval)
+	}
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Op&fsnotify.Create != 0 {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					_ = watcher.Add(event.Name)
+				}
+			}
+			scheduleReload()
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			logger.Errorf("memfs: fsnotify error: %v", err)
+		case <-sighup:
+			logger.Info("memfs: SIGHUP received, forcing reload")
+			if err := Reload(rootDir); err != nil {
+				logger.Errorf("memfs: reload failed: %v", err)
+			}
+		case <-reload:
+			if err := Reload(rootDir); err != nil {
+				logger.Errorf("memfs: reload failed: %v", err)
+			}
+		}
+	}
+}
+
+func addRecursive(watcher *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return watcher.Add(path)
+		}
+		return nil
+	})
+}
+
+// AdminReloadHandler forces a full rescan of rootDir when called with POST
+// and the token configured via config.CFG.AdminToken. It is meant to be
+// mounted on the metrics port only, never the public one.
+func AdminReloadHandler(rootDir string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if config.CFG.AdminToken == "" || r.Header.Get("X-Admin-Token") != config.CFG.AdminToken {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		if err := Reload(rootDir); err != nil {
+			logger.Errorf("memfs: admin-triggered reload failed: %v", err)
+			http.Error(w, "reload failed", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintln(w, "reloaded")
+	}
+}
+
+// incompressibleTypePrefixes are top-level MIME types that are already
+// compressed (or gain nothing from it), so precompression is skipped for them.
+var incompressibleTypePrefixes = []string{"image/", "video/", "audio/"}
+
+// incompressibleTypes are specific MIME types, outside the prefixes above,
+// that are already compressed.
+var incompressibleTypes = map[string]bool{
+	"application/zip":    true,
+	"application/gzip":   true,
+	"application/x-gzip": true,
+}
+
+// shouldCompress reports whether content of the given type and size is worth
+// precomputing gzip/brotli variants for.
+func shouldCompress(contentType string, size int) bool {
+	if size < config.CFG.CompressMinBytes {
+		return false
+	}
+
+	ct := strings.ToLower(contentType)
+	if idx := strings.Index(ct, ";"); idx != -1 {
+		ct = ct[:idx]
+	}
+
+	for _, prefix := range incompressibleTypePrefixes {
+		if strings.HasPrefix(ct, prefix) {
+			return false
+		}
+	}
+
+	return !incompressibleTypes[ct]
+}
+
+// gzipCompress returns the gzip-compressed form of content, or nil if
+// compression fails.
+func gzipCompress(content []byte) []byte {
+	var buf bytes.Buffer
+	gw, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return nil
+	}
+	if _, err := gw.Write(content); err != nil {
+		return nil
+	}
+	if err := gw.Close(); err != nil {
+		return nil
+	}
+	return buf.Bytes()
+}
+
+// brotliCompress returns the brotli-compressed form of content, or nil if
+// compression fails.
+func brotliCompress(content []byte) []byte {
+	var buf bytes.Buffer
+	bw := brotli.NewWriterLevel(&buf, brotli.BestCompression)
+	if _, err := bw.Write(content); err != nil {
+		return nil
+	}
+	if err := bw.Close(); err != nil {
+		return nil
+	}
+	return buf.Bytes()
+}
+
+// newFileData builds a FileData for content, precomputing gzip/brotli
+// variants and a sha256 digest used as a strong ETag.
+func newFileData(content []byte) *FileData {
+	contentType := http.DetectContentType(content)
+	sum := sha256.Sum256(content)
+
+	fd := &FileData{
+		ContentType: contentType,
+		Raw:         content,
+		SHA256:      hex.EncodeToString(sum[:]),
+		ModTime:     time.Now(),
+	}
+
+	if shouldCompress(contentType, len(content)) {
+		fd.Gzip = gzipCompress(content)
+		fd.Br = brotliCompress(content)
+	}
+
+	return fd
+}
@@ -9,6 +9,8 @@ import (
 	"github.com/supporttools/website/pkg/config"
 	"github.com/supporttools/website/pkg/health"
 	"github.com/supporttools/website/pkg/logging"
+	"github.com/supporttools/website/pkg/maintenance"
+	"github.com/supporttools/website/pkg/memfs"
 )
 
 var logger = logging.SetupLogging(&config.CFG)
@@ -44,8 +46,14 @@ func StartMetricsServer(port int) {
 	mux := http.NewServeMux()
 	mux.Handle("/metrics", promhttp.Handler())
 	mux.Handle("/healthz", health.HealthzHandler())
+	mux.Handle("/readyz", health.ReadyzHandler())
 	mux.Handle("/version", health.VersionHandler())
 
+	if config.CFG.UseMemory {
+		mux.Handle("/admin/reload", memfs.AdminReloadHandler(config.CFG.WebRoot))
+	}
+	mux.Handle("/admin/maintenance", maintenance.AdminHandler())
+
 	serverPort := strconv.Itoa(port)
 	logger.Printf("Metrics server starting on port %d\n", port)
 